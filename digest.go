@@ -0,0 +1,162 @@
+// package latencyTracker
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// defaultCompression is the t-digest compression parameter (delta) used when
+// a MetricConfig doesn't set one via MaxSamples. Higher values trade more
+// centroids (memory) for sharper quantile estimates.
+const defaultCompression = 100
+
+// centroid is a weighted mean: "weight" observations landed close enough
+// together to be summarized as a single point at "mean".
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// tdigest is Dunning & Ertl's t-digest sketch: a bounded-memory summary of a
+// distribution that stays accurate at the tails (where percentiles like P99
+// matter most) by allotting extreme centroids far less weight than central
+// ones. compression (delta) controls the size/accuracy trade-off.
+type tdigest struct {
+	compression float64
+	centroids   []centroid // always kept sorted by mean
+	count       float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	if compression <= 0 {
+		compression = defaultCompression
+	}
+	return &tdigest{compression: compression}
+}
+
+// insert folds a single observation into the digest, merging it into the
+// nearest centroid when that centroid has room to grow, or inserting a new
+// centroid otherwise. Compresses once the centroid count grows past ~20*delta.
+func (td *tdigest) insert(value float64) {
+	td.count++
+
+	if len(td.centroids) == 0 {
+		td.centroids = append(td.centroids, centroid{mean: value, weight: 1})
+		return
+	}
+
+	closest, cumWeightBefore := td.nearest(value)
+	c := td.centroids[closest]
+	q := (cumWeightBefore + c.weight/2) / td.count
+	maxWeight := 4 * td.count * q * (1 - q) / td.compression
+	if maxWeight < 1 {
+		maxWeight = 1
+	}
+
+	if c.weight+1 <= maxWeight {
+		newWeight := c.weight + 1
+		c.mean += (value - c.mean) / newWeight
+		c.weight = newWeight
+		td.centroids[closest] = c
+	} else {
+		idx := sort.Search(len(td.centroids), func(i int) bool { return td.centroids[i].mean >= value })
+		td.centroids = append(td.centroids, centroid{})
+		copy(td.centroids[idx+1:], td.centroids[idx:])
+		td.centroids[idx] = centroid{mean: value, weight: 1}
+	}
+
+	if len(td.centroids) > int(20*td.compression) {
+		td.compress()
+	}
+}
+
+// nearest returns the index of the centroid closest to value, along with the
+// cumulative weight of every centroid before it.
+func (td *tdigest) nearest(value float64) (index int, cumWeightBefore float64) {
+	bestDist := math.Inf(1)
+	cum := 0.0
+	for i, c := range td.centroids {
+		if d := math.Abs(c.mean - value); d < bestDist {
+			bestDist = d
+			index = i
+			cumWeightBefore = cum
+		}
+		cum += c.weight
+	}
+	return index, cumWeightBefore
+}
+
+// compress greedily merges adjacent centroids (they're kept sorted by mean)
+// back down to the size limit implied by the same weight rule used on insert.
+func (td *tdigest) compress() {
+	if len(td.centroids) <= 1 {
+		return
+	}
+	merged := make([]centroid, 0, len(td.centroids))
+	cumWeight := 0.0
+	for _, c := range td.centroids {
+		if len(merged) == 0 {
+			merged = append(merged, c)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		q := (cumWeight + last.weight/2) / td.count
+		maxWeight := 4 * td.count * q * (1 - q) / td.compression
+		if maxWeight < 1 {
+			maxWeight = 1
+		}
+		if last.weight+c.weight <= maxWeight {
+			newWeight := last.weight + c.weight
+			last.mean += (c.mean - last.mean) * (c.weight / newWeight)
+			last.weight = newWeight
+		} else {
+			cumWeight += last.weight
+			merged = append(merged, c)
+		}
+	}
+	td.centroids = merged
+}
+
+// merge folds another digest's centroids into this one and re-compresses.
+func (td *tdigest) merge(other *tdigest) {
+	if other == nil || len(other.centroids) == 0 {
+		return
+	}
+	td.centroids = append(td.centroids, other.centroids...)
+	sort.Slice(td.centroids, func(i, j int) bool { return td.centroids[i].mean < td.centroids[j].mean })
+	td.count += other.count
+	td.compress()
+}
+
+// quantile estimates the value at quantile q (0..1) by walking centroids in
+// sorted order and linearly interpolating between the two whose cumulative
+// weight straddles q*count. Returns ok=false for an empty digest.
+func (td *tdigest) quantile(q float64) (float64, bool) {
+	if len(td.centroids) == 0 {
+		return 0, false
+	}
+	if q <= 0 {
+		return td.centroids[0].mean, true
+	}
+	if q >= 1 {
+		return td.centroids[len(td.centroids)-1].mean, true
+	}
+
+	target := q * td.count
+	cumWeightBefore := 0.0
+	for i, c := range td.centroids {
+		mid := cumWeightBefore + c.weight/2
+		if mid >= target {
+			if i == 0 {
+				return c.mean, true
+			}
+			prev := td.centroids[i-1]
+			prevMid := cumWeightBefore - prev.weight/2
+			frac := (target - prevMid) / (mid - prevMid)
+			return prev.mean + frac*(c.mean-prev.mean), true
+		}
+		cumWeightBefore += c.weight
+	}
+	return td.centroids[len(td.centroids)-1].mean, true
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// Test quantile estimates on a uniform distribution land close to the
+// expected value, within the slack a sketch (vs. exact sort) is allowed.
+func TestTDigestQuantileUniform(t *testing.T) {
+	td := newTDigest(100)
+	for i := 1; i <= 1000; i++ {
+		td.insert(float64(i))
+	}
+
+	cases := map[float64]float64{
+		0.50: 500,
+		0.95: 950,
+		0.99: 990,
+	}
+	for q, want := range cases {
+		got, ok := td.quantile(q)
+		if !ok {
+			t.Fatalf("quantile(%v) reported not ok", q)
+		}
+		if math.Abs(got-want) > 30 {
+			t.Errorf("quantile(%v) = %v, want close to %v", q, got, want)
+		}
+	}
+}
+
+// Test an empty digest reports no quantile.
+func TestTDigestEmptyQuantile(t *testing.T) {
+	td := newTDigest(100)
+	if _, ok := td.quantile(0.5); ok {
+		t.Errorf("expected empty digest to report ok=false")
+	}
+}
+
+// Test merge combines two digests' weight and centroids.
+func TestTDigestMerge(t *testing.T) {
+	a := newTDigest(100)
+	b := newTDigest(100)
+	for i := 1; i <= 500; i++ {
+		a.insert(float64(i))
+	}
+	for i := 501; i <= 1000; i++ {
+		b.insert(float64(i))
+	}
+	a.merge(b)
+
+	if a.count != 1000 {
+		t.Errorf("Expected merged count 1000, got %v", a.count)
+	}
+	got, ok := a.quantile(0.5)
+	if !ok {
+		t.Fatalf("quantile(0.5) reported not ok")
+	}
+	if math.Abs(got-500) > 30 {
+		t.Errorf("quantile(0.5) after merge = %v, want close to 500", got)
+	}
+}
+
+// Test the digest stays bounded in size well below the raw sample count.
+func TestTDigestBoundedSize(t *testing.T) {
+	td := newTDigest(100)
+	for i := 0; i < 100000; i++ {
+		td.insert(float64(i % 1000))
+	}
+	if len(td.centroids) > 20*100 {
+		t.Errorf("Expected centroid count to stay bounded, got %d", len(td.centroids))
+	}
+}
@@ -2,21 +2,51 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"time"
+
+	"github.com/ashutosh-pandey10/latency-tracker/otlp"
 )
 
 func main() {
-	store := &MetricStore{}
+	otlpEndpoint := flag.String("otlp-endpoint", "", "OpenTelemetry collector gRPC endpoint (host:port) to export metrics to; exporting is disabled when left empty")
+	otlpInterval := flag.Duration("otlp-interval", 15*time.Second, "how often to export metrics when -otlp-endpoint is set")
+	flag.Parse()
+
+	store := createNewMetricStore()
 	server := Server{store}
 
 	router := http.NewServeMux()
 	router.HandleFunc("GET /metrics", server.handleMetrics)
+	router.HandleFunc("GET /metrics/prometheus", server.handleMetricsPrometheus)
+	router.HandleFunc("GET /metrics/subscribe", server.handleSubscribeAll)
 	// Golang, more specifically ServerMux inherently cannot handle path
 	// parameters, so we pass either prefix/suffix of an endpoint and later
 	// handle it inside the handler which is passed in "HandleFunc()"
 	router.HandleFunc("/metrics/", server.handleMetricsById)
 
+	// Wrapping the router in our own Middleware means the tracker measures
+	// its own handlers (including "/metrics" itself) the same way it'd
+	// measure any other instrumented service.
+	instrumented := Middleware(store, nil)(router)
+
+	if *otlpEndpoint != "" {
+		exporter, err := otlp.NewExporter(store, *otlpEndpoint, *otlpInterval, map[string]string{"service.name": "latency-tracker"})
+		if err != nil {
+			log.Fatalf("otlp: %v", err)
+		}
+		exporter.Start(context.Background())
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			exporter.Shutdown(ctx)
+		}()
+		log.Printf("Exporting metrics to OTLP collector at %s every %s", *otlpEndpoint, *otlpInterval)
+	}
+
 	log.Println("Listening on port 8080...")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	log.Fatal(http.ListenAndServe(":8080", instrumented))
 }
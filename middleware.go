@@ -0,0 +1,97 @@
+// package latencyTracker
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps next with automatic latency instrumentation: every
+// request is timed with time.Since and recorded against
+// store.getOrCreateMetric(idFn(r)), bucketed by the response status code.
+// idFn defaults to defaultIDFn when nil.
+//
+// Hijacked connections (e.g. a websocket upgrade) are not recorded, since
+// wall-clock "request latency" stops meaning anything once the handler has
+// taken over the raw connection.
+func Middleware(store *MetricStore, idFn func(*http.Request) string) func(http.Handler) http.Handler {
+	if idFn == nil {
+		idFn = defaultIDFn
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			shim := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+
+			next.ServeHTTP(shim, r)
+
+			if shim.hijacked {
+				return
+			}
+
+			route := idFn(r)
+			store.getOrCreateMetric(route).RecordSample(LatencySample{
+				LatencyVal: time.Since(start),
+				RecordedAt: start,
+				Status:     shim.status,
+			})
+		})
+	}
+}
+
+// defaultIDFn groups latency by route: the matched http.ServeMux pattern
+// (e.g. "GET /widgets/{id}"), which keeps one metric per route regardless of
+// how many concrete paths it matches, falling back to the raw URL path for
+// handlers not registered through a pattern-aware router (r.Pattern is only
+// populated when the request was dispatched by an http.ServeMux).
+func defaultIDFn(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	if r.URL != nil && r.URL.Path != "" {
+		return r.URL.Path
+	}
+	return "unknown"
+}
+
+// responseWriter shims http.ResponseWriter to capture the status code a
+// downstream handler wrote, and to track whether the connection was
+// hijacked (e.g. for a websocket upgrade), in which case Middleware skips
+// recording a latency sample.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	hijacked    bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack satisfies http.Hijacker so handlers upgrading to a raw connection
+// (e.g. websockets) keep working through the shim; Middleware uses the
+// hijacked flag to skip recording a latency sample for the request.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support hijacking")
+	}
+	w.hijacked = true
+	return hijacker.Hijack()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test Middleware records a latency sample, bucketed by status, against the
+// route it instrumented.
+func TestMiddlewareRecordsLatency(t *testing.T) {
+	store := createNewMetricStore()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("ok"))
+	})
+
+	wrapped := Middleware(store, nil)(handler)
+
+	req := httptest.NewRequest("GET", "/widgets", nil)
+	w := httptest.NewRecorder()
+	wrapped.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 from the wrapped handler, got %d", w.Code)
+	}
+
+	metric, ok := store.getMetric("/widgets")
+	if !ok {
+		t.Fatalf("Expected Middleware to create a metric for /widgets")
+	}
+	if metric.Count != 1 {
+		t.Errorf("Expected 1 recorded sample, got %d", metric.Count)
+	}
+	if metric.StatusCounts[http.StatusCreated] != 1 {
+		t.Errorf("Expected 1 sample bucketed under status 201, got %d", metric.StatusCounts[http.StatusCreated])
+	}
+}
+
+// Test that requests matched through a wildcard http.ServeMux pattern are
+// grouped under one metric keyed by the pattern (e.g. "GET /widgets/{id}"),
+// rather than one metric per concrete path, by exercising Middleware wrapped
+// around a real ServeMux instead of calling a bare handler directly.
+func TestMiddlewareGroupsByMuxPattern(t *testing.T) {
+	store := createNewMetricStore()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /widgets/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	wrapped := Middleware(store, nil)(mux)
+
+	for _, id := range []string{"1", "2", "3"} {
+		req := httptest.NewRequest("GET", "/widgets/"+id, nil)
+		w := httptest.NewRecorder()
+		wrapped.ServeHTTP(w, req)
+	}
+
+	metric, ok := store.getMetric("GET /widgets/{id}")
+	if !ok {
+		t.Fatalf("Expected a single metric keyed by the matched mux pattern")
+	}
+	if metric.Count != 3 {
+		t.Errorf("Expected all 3 requests grouped under the pattern's metric, got count %d", metric.Count)
+	}
+	if _, ok := store.getMetric("/widgets/1"); ok {
+		t.Errorf("Did not expect a separate metric per concrete path")
+	}
+}
+
+// Test a custom idFn is honored instead of the default path-based one.
+func TestMiddlewareCustomIDFn(t *testing.T) {
+	store := createNewMetricStore()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	wrapped := Middleware(store, func(r *http.Request) string { return "custom-id" })(handler)
+
+	req := httptest.NewRequest("GET", "/anything", nil)
+	w := httptest.NewRecorder()
+	wrapped.ServeHTTP(w, req)
+
+	if _, ok := store.getMetric("custom-id"); !ok {
+		t.Errorf("Expected a metric recorded under the custom id")
+	}
+	if _, ok := store.getMetric("/anything"); ok {
+		t.Errorf("Did not expect a metric recorded under the default path-based id")
+	}
+}
+
+// Test a hijacked connection is not recorded as a latency sample.
+func TestMiddlewareSkipsHijackedConnections(t *testing.T) {
+	store := createNewMetricStore()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("expected the shimmed ResponseWriter to support hijacking")
+		}
+		conn, buf, err := hj.Hijack()
+		if err != nil {
+			t.Fatalf("Hijack failed: %v", err)
+		}
+		defer conn.Close()
+		buf.Flush()
+	})
+
+	wrapped := Middleware(store, nil)(handler)
+
+	server := httptest.NewServer(wrapped)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial test server: %v", err)
+	}
+	defer conn.Close()
+
+	req, _ := http.NewRequest("GET", "/ws", nil)
+	req.Write(conn)
+	bufio.NewReader(conn).ReadByte()
+
+	if _, ok := store.getMetric("/ws"); ok {
+		t.Errorf("Did not expect a latency sample for a hijacked connection")
+	}
+}
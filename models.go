@@ -7,17 +7,33 @@ import (
 )
 
 type MetricConfig struct {
-	ID          string
-	Window      time.Duration
-	Percentiles []int // These are the percentiles for which we'll calculate latency
-	MaxSamples  int   // Won't accept more that this number of latency values for calculating
-	// percentile
+	ID     string
+	Window time.Duration
+	// Percentiles are the percentiles CalculateLatency reports, e.g. [50, 95, 99].
+	// Defaults to [50, 95, 99] when left empty.
+	Percentiles []int
+	// MaxSamples is the t-digest compression parameter (delta): higher values
+	// keep more centroids (see digest.go) and so sharper percentile estimates,
+	// at the cost of more memory per sub-digest. Formerly a hard cap on the
+	// number of raw samples retained; repurposed once percentiles moved off
+	// raw samples onto sketches.
+	MaxSamples int
+	// Buckets are the upper bounds ("le") of the cumulative histogram exposed
+	// at "/metrics/prometheus", in ascending order. A final "+Inf" bucket is
+	// always added implicitly, so it doesn't need to be listed here.
+	Buckets   []time.Duration
 	CreatedAt time.Time
 }
 
 type LatencySample struct {
 	LatencyVal time.Duration
 	RecordedAt time.Time
+
+	// Status is optional and populated by Middleware so latency can be
+	// bucketed per HTTP status (see Metric.StatusCounts); it's zero-valued
+	// when a latency is recorded directly via the "/metrics/{id}/latency"
+	// API.
+	Status int
 }
 
 // NOTE: Rule of thumb (important):
@@ -27,9 +43,35 @@ type LatencySample struct {
 // consistent, there isn't alot of appending/re-slicing, and the data stru-
 // cture is small in size
 type Metric struct {
-	Config  MetricConfig
-	Samples []LatencySample
-	mu      sync.Mutex
+	Config MetricConfig
+
+	// digests backs CalculateLatency's percentiles: a ring of per-second
+	// t-digest sketches (see digest.go), keyed by the bucket's start time
+	// (truncated to digestBucket), instead of the raw LatencySample slice
+	// this used to be. Keeping bounded-size sketches instead of every sample
+	// caps memory regardless of traffic volume and avoids sorting on every
+	// read. Sub-digests that fall outside Window are evicted, not decayed.
+	digests map[int64]*tdigest
+
+	// Histogram state backing "/metrics/prometheus". Unlike digests, which
+	// are windowed and evicted at read time, these are cumulative-since-start
+	// counters so they behave like a Prometheus histogram is expected to
+	// (monotonically increasing, safe to rate()/increase() over). BucketCounts
+	// is parallel to Config.Buckets, plus one trailing "+Inf" slot.
+	BucketCounts []uint64
+	Sum          time.Duration
+	Count        uint64
+
+	// StatusCounts tallies observations by LatencySample.Status, e.g. so
+	// Middleware can report how many requests against a route came back as
+	// a 5xx. Only populated for samples recorded with a non-zero Status.
+	StatusCounts map[int]uint64
+
+	// subscribers counts active MetricStore.Subscribe listeners for this
+	// metric, so one metric can't accumulate unbounded ticker goroutines.
+	subscribers int
+
+	mu sync.Mutex
 }
 
 // It is very import in terms of judging performance that we minimize the
@@ -40,6 +82,11 @@ type Metric struct {
 type MetricStore struct {
 	Metrics map[string]*Metric // The string in the map is essentially the ID assigned to MetricConfig
 	mu      sync.RWMutex
+
+	// allSubscribers counts active handleSubscribeAll connections, so that
+	// endpoint can be capped the same way Metric.subscribers caps
+	// handleSubscribe's per-metric connections (see AcquireGlobalSubscriber).
+	allSubscribers int
 }
 
 type MetricSnapshot struct {
@@ -52,4 +99,13 @@ type MetricSnapshot struct {
 	P50 *time.Duration // Pointer fields allow “not available yet”
 	P95 *time.Duration
 	P99 *time.Duration
+
+	// Percentiles holds every percentile configured via MetricConfig.Percentiles
+	// (defaulting to 50/95/99), keyed by the whole-number percentile requested.
+	// P50/P95/P99 above are kept for existing consumers of this shape.
+	Percentiles map[int]time.Duration
+
+	// StatusCounts mirrors Metric.StatusCounts: counts of recorded samples by
+	// HTTP status, populated when latencies are recorded via Middleware.
+	StatusCounts map[int]uint64
 }
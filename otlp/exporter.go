@@ -0,0 +1,284 @@
+// Package otlp periodically pushes the latency tracker's metrics to an
+// OpenTelemetry collector over gRPC, so existing OTel pipelines can ingest
+// them alongside everything else they already collect.
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// scopeName identifies this exporter as the instrumentation scope attached
+// to every metric it emits, the way an OTel SDK would stamp its own name.
+const scopeName = "github.com/ashutosh-pandey10/latency-tracker"
+
+// MetricExport is the shape Store.Export hands the exporter: everything one
+// tick needs to build a ResourceMetrics entry for a single tracked metric,
+// without the otlp package needing to import package main (MetricStore and
+// Metric live there, and a "main" package can't be imported by anything
+// else) or main needing to import the OTLP protobuf types itself.
+type MetricExport struct {
+	ID    string
+	Count uint64
+	Sum   time.Duration
+
+	// Buckets/BucketCounts mirror the Prometheus-histogram feature
+	// (MetricConfig.Buckets / Metric.BucketCounts): when Buckets is
+	// non-empty the metric is exported as an OTLP Histogram, reusing these
+	// cumulative counters rather than re-deriving them from percentiles.
+	Buckets      []time.Duration
+	BucketCounts []uint64
+
+	// Percentiles is used instead, as an OTLP Summary, when Buckets is empty.
+	Percentiles map[int]time.Duration
+}
+
+// Store is the subset of MetricStore's behavior the exporter needs. Defined
+// as an interface, rather than taking *MetricStore directly, so package main
+// can satisfy it without this package importing package main.
+type Store interface {
+	Export() []MetricExport
+}
+
+// Exporter periodically converts every MetricExport in a Store into OTLP
+// metric data and pushes it to a collector endpoint over gRPC.
+type Exporter struct {
+	store    Store
+	interval time.Duration
+	timeout  time.Duration
+	resource *resourcepb.Resource
+
+	conn   *grpc.ClientConn
+	client colmetricpb.MetricsServiceClient
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// defaultExportTimeout bounds a single tick's export call (including
+// retries) so a stalled collector can't pile up goroutines tick over tick.
+const defaultExportTimeout = 10 * time.Second
+
+// maxExportAttempts is how many times Export retries a transient gRPC error
+// before giving up on a tick.
+const maxExportAttempts = 3
+
+// initialBackoff is the delay before the first retry; it doubles on each
+// subsequent attempt.
+const initialBackoff = 100 * time.Millisecond
+
+// NewExporter dials endpoint (a "host:port" OpenTelemetry collector gRPC
+// address) and returns an Exporter that, once started, exports every metric
+// in store every interval. resource is attached to every export as OTLP
+// resource attributes (e.g. {"service.name": "latency-tracker"}).
+func NewExporter(store Store, endpoint string, interval time.Duration, resource map[string]string) (*Exporter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("otlp: interval must be positive, got %s", interval)
+	}
+	conn, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("otlp: dialing collector %q: %w", endpoint, err)
+	}
+	return &Exporter{
+		store:    store,
+		interval: interval,
+		timeout:  defaultExportTimeout,
+		resource: resourceProto(resource),
+		conn:     conn,
+		client:   colmetricpb.NewMetricsServiceClient(conn),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start runs the export loop until ctx is cancelled or Shutdown is called.
+// It returns immediately; the loop runs in its own goroutine.
+func (e *Exporter) Start(ctx context.Context) {
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				if err := e.exportOnce(ctx); err != nil {
+					fmt.Printf("otlp: export failed: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Shutdown stops the export loop and closes the underlying gRPC connection.
+// It waits for an in-flight tick to finish, or ctx to be done, whichever
+// comes first.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	return e.conn.Close()
+}
+
+// exportOnce builds one ExportMetricsServiceRequest from the current state
+// of the store and sends it, retrying transient gRPC errors with backoff
+// until maxExportAttempts is exhausted or the per-export timeout elapses.
+func (e *Exporter) exportOnce(ctx context.Context) error {
+	exports := e.store.Export()
+	if len(exports) == 0 {
+		return nil
+	}
+	req := &colmetricpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{buildResourceMetrics(exports, e.resource)},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	backoff := initialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= maxExportAttempts; attempt++ {
+		_, err := e.client.Export(ctx, req)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !isTransient(err) || attempt == maxExportAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("otlp: export failed after %d attempt(s): %w", maxExportAttempts, lastErr)
+}
+
+// isTransient reports whether err is a gRPC status worth retrying, as
+// opposed to one that will just fail the same way again (e.g. an
+// InvalidArgument from a malformed request).
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// buildResourceMetrics converts a tick's worth of MetricExports into a
+// single ResourceMetrics under one instrumentation scope.
+func buildResourceMetrics(exports []MetricExport, resource *resourcepb.Resource) *metricpb.ResourceMetrics {
+	metrics := make([]*metricpb.Metric, 0, len(exports))
+	for _, ex := range exports {
+		metrics = append(metrics, buildMetric(ex))
+	}
+	return &metricpb.ResourceMetrics{
+		Resource: resource,
+		ScopeMetrics: []*metricpb.ScopeMetrics{
+			{
+				Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+				Metrics: metrics,
+			},
+		},
+	}
+}
+
+// buildMetric converts a single MetricExport into an OTLP Metric: a
+// Histogram when bucket counts are available (reusing the Prometheus
+// histogram feature), otherwise a Summary built from configured
+// percentiles.
+func buildMetric(ex MetricExport) *metricpb.Metric {
+	now := uint64(time.Now().UnixNano())
+	m := &metricpb.Metric{
+		Name:        "latency",
+		Description: "Observed latency for metric " + ex.ID,
+		Unit:        "ns",
+	}
+
+	attrs := []*commonpb.KeyValue{stringAttr("id", ex.ID)}
+
+	if len(ex.Buckets) > 0 {
+		bounds := make([]float64, len(ex.Buckets))
+		for i, b := range ex.Buckets {
+			bounds[i] = float64(b.Nanoseconds())
+		}
+		sum := float64(ex.Sum.Nanoseconds())
+		m.Data = &metricpb.Metric_Histogram{
+			Histogram: &metricpb.Histogram{
+				AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints: []*metricpb.HistogramDataPoint{
+					{
+						Attributes:     attrs,
+						TimeUnixNano:   now,
+						Count:          ex.Count,
+						Sum:            &sum,
+						BucketCounts:   ex.BucketCounts,
+						ExplicitBounds: bounds,
+					},
+				},
+			},
+		}
+		return m
+	}
+
+	quantiles := make([]*metricpb.SummaryDataPoint_ValueAtQuantile, 0, len(ex.Percentiles))
+	for p, v := range ex.Percentiles {
+		quantiles = append(quantiles, &metricpb.SummaryDataPoint_ValueAtQuantile{
+			Quantile: float64(p) / 100,
+			Value:    float64(v.Nanoseconds()),
+		})
+	}
+	m.Data = &metricpb.Metric_Summary{
+		Summary: &metricpb.Summary{
+			DataPoints: []*metricpb.SummaryDataPoint{
+				{
+					Attributes:     attrs,
+					TimeUnixNano:   now,
+					Count:          ex.Count,
+					Sum:            float64(ex.Sum.Nanoseconds()),
+					QuantileValues: quantiles,
+				},
+			},
+		},
+	}
+	return m
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// resourceProto converts the caller's resource attributes into an OTLP
+// Resource. A nil/empty map yields a nil Resource (OTLP treats that as "no
+// resource info known"), same as an unset field.
+func resourceProto(resource map[string]string) *resourcepb.Resource {
+	if len(resource) == 0 {
+		return nil
+	}
+	attrs := make([]*commonpb.KeyValue, 0, len(resource))
+	for k, v := range resource {
+		attrs = append(attrs, stringAttr(k, v))
+	}
+	return &resourcepb.Resource{Attributes: attrs}
+}
@@ -0,0 +1,227 @@
+package otlp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeStore is a minimal otlp.Store for tests, standing in for a real
+// MetricStore without this package needing to import package main.
+type fakeStore struct {
+	exports []MetricExport
+}
+
+func (s *fakeStore) Export() []MetricExport { return s.exports }
+
+// fakeCollector is a Export-only MetricsServiceServer that records every
+// request it receives, standing in for an OpenTelemetry collector. The
+// exporter speaks gRPC (see NewExporter), so the receiver here is a real
+// in-process gRPC server rather than an httptest.Server, which only serves
+// plain HTTP/1.1 and can't terminate a gRPC client connection.
+type fakeCollector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*colmetricpb.ExportMetricsServiceRequest
+	failN    int // fail the first failN calls with a transient error
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failN > 0 {
+		c.failN--
+		return nil, status.Error(codes.Unavailable, "collector temporarily unavailable")
+	}
+	c.requests = append(c.requests, req)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *fakeCollector) received() []*colmetricpb.ExportMetricsServiceRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*colmetricpb.ExportMetricsServiceRequest, len(c.requests))
+	copy(out, c.requests)
+	return out
+}
+
+// startCollector spins up fakeCollector on an in-process gRPC server and
+// returns its address and a cleanup func.
+func startCollector(t *testing.T, collector *fakeCollector) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(srv, collector)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func waitForRequests(t *testing.T, collector *fakeCollector, n int) []*colmetricpb.ExportMetricsServiceRequest {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if reqs := collector.received(); len(reqs) >= n {
+			return reqs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d export request(s), got %d", n, len(collector.received()))
+	return nil
+}
+
+// Test a histogram-shaped MetricExport (Buckets present) is exported as an
+// OTLP Histogram data point carrying the bucket counts/sum/count through.
+func TestExporterExportsHistogram(t *testing.T) {
+	collector := &fakeCollector{}
+	addr := startCollector(t, collector)
+
+	store := &fakeStore{exports: []MetricExport{
+		{
+			ID:           "checkout",
+			Count:        3,
+			Sum:          300 * time.Millisecond,
+			Buckets:      []time.Duration{50 * time.Millisecond, 100 * time.Millisecond},
+			BucketCounts: []uint64{1, 2, 3},
+		},
+	}}
+
+	exporter, err := NewExporter(store, addr, 20*time.Millisecond, map[string]string{"service.name": "checkout-svc"})
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.Start(ctx)
+	defer cancel()
+
+	reqs := waitForRequests(t, collector, 1)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	if err := exporter.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	rm := reqs[0].ResourceMetrics
+	if len(rm) != 1 {
+		t.Fatalf("expected 1 ResourceMetrics, got %d", len(rm))
+	}
+	if got := rm[0].Resource.Attributes[0].Value.GetStringValue(); got != "checkout-svc" {
+		t.Errorf("expected resource attribute 'checkout-svc', got %q", got)
+	}
+
+	metrics := rm[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 Metric, got %d", len(metrics))
+	}
+	hist, ok := metrics[0].Data.(*metricpb.Metric_Histogram)
+	if !ok {
+		t.Fatalf("expected Histogram data, got %T", metrics[0].Data)
+	}
+	dp := hist.Histogram.DataPoints[0]
+	if dp.Count != 3 {
+		t.Errorf("expected count 3, got %d", dp.Count)
+	}
+	if len(dp.BucketCounts) != 3 || dp.BucketCounts[2] != 3 {
+		t.Errorf("expected bucket counts [1 2 3], got %v", dp.BucketCounts)
+	}
+}
+
+// Test a percentile-only MetricExport (no Buckets) is exported as an OTLP
+// Summary with one ValueAtQuantile per configured percentile.
+func TestExporterExportsSummary(t *testing.T) {
+	collector := &fakeCollector{}
+	addr := startCollector(t, collector)
+
+	store := &fakeStore{exports: []MetricExport{
+		{
+			ID:          "search",
+			Count:       2,
+			Sum:         40 * time.Millisecond,
+			Percentiles: map[int]time.Duration{50: 15 * time.Millisecond, 99: 30 * time.Millisecond},
+		},
+	}}
+
+	exporter, err := NewExporter(store, addr, 20*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.Start(ctx)
+	defer cancel()
+
+	reqs := waitForRequests(t, collector, 1)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	exporter.Shutdown(shutdownCtx)
+
+	metric := reqs[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	summary, ok := metric.Data.(*metricpb.Metric_Summary)
+	if !ok {
+		t.Fatalf("expected Summary data, got %T", metric.Data)
+	}
+	dp := summary.Summary.DataPoints[0]
+	if len(dp.QuantileValues) != 2 {
+		t.Fatalf("expected 2 quantile values, got %d", len(dp.QuantileValues))
+	}
+}
+
+// Test a transient collector error is retried, and a tick succeeds once the
+// collector recovers within maxExportAttempts.
+func TestExporterRetriesTransientErrors(t *testing.T) {
+	collector := &fakeCollector{failN: 1}
+	addr := startCollector(t, collector)
+
+	store := &fakeStore{exports: []MetricExport{
+		{ID: "flaky", Count: 1, Sum: time.Millisecond, Percentiles: map[int]time.Duration{50: time.Millisecond}},
+	}}
+
+	exporter, err := NewExporter(store, addr, 20*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.Start(ctx)
+	defer cancel()
+
+	waitForRequests(t, collector, 1)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	exporter.Shutdown(shutdownCtx)
+}
+
+// Test an empty store produces no export calls at all.
+func TestExporterSkipsEmptyStore(t *testing.T) {
+	collector := &fakeCollector{}
+	addr := startCollector(t, collector)
+
+	store := &fakeStore{}
+	exporter, err := NewExporter(store, addr, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.Start(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	exporter.Shutdown(shutdownCtx)
+
+	if len(collector.received()) != 0 {
+		t.Errorf("expected no export requests for an empty store, got %d", len(collector.received()))
+	}
+}
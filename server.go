@@ -26,6 +26,7 @@ func defaultMetricConfig(id string) MetricConfig {
 		ID:         id,
 		Window:     time.Minute,
 		MaxSamples: 100,
+		Buckets:    defaultBuckets,
 		CreatedAt:  time.Now(),
 	}
 }
@@ -64,6 +65,7 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 // Expected paths:
 // "/metrics/{id}"
 // "/metrics/{id}/latency"
+// "/metrics/{id}/subscribe"
 func (s *Server) handleMetricsById(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	comps := strings.Split(strings.Trim(path, "/"), "/")
@@ -80,6 +82,10 @@ func (s *Server) handleMetricsById(w http.ResponseWriter, r *http.Request) {
 		s.handleRecordLatency(w, r, id)
 		return
 	}
+	if len(comps) == 3 && comps[2] == "subscribe" && r.Method == http.MethodGet {
+		s.handleSubscribe(w, r, id)
+		return
+	}
 	if len(comps) == 2 && r.Method == http.MethodGet {
 		// This is a GET request for querying calculating latncy
 		s.handleGetMetric(w, r, id)
@@ -107,6 +113,28 @@ func (s *Server) handleRecordLatency(w http.ResponseWriter, r *http.Request, id
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// handleMetricsPrometheus renders every Metric in the store in the
+// Prometheus text exposition format, so the tracker can be scraped directly
+// without a sidecar. See writePrometheus for the exact series emitted.
+func (s *Server) handleMetricsPrometheus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only 'GET' requests are processed at this endpoint!", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.store.mu.RLock()
+	metrics := make([]*Metric, 0, len(s.store.Metrics))
+	for _, m := range s.store.Metrics {
+		metrics = append(metrics, m)
+	}
+	s.store.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := writePrometheus(w, metrics); err != nil {
+		http.Error(w, "failed to render metrics", http.StatusInternalServerError)
+	}
+}
+
 func (s *Server) handleGetMetric(w http.ResponseWriter, r *http.Request, id string) {
 	metric, ok := s.store.getMetric(id)
 	if !ok {
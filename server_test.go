@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -32,8 +33,8 @@ func TestHandleRecordLatencyValid(t *testing.T) {
 		t.Errorf("Expected metric to be created")
 	}
 
-	if len(metric.Samples) != 1 {
-		t.Errorf("Expected 1 sample, got %d", len(metric.Samples))
+	if metric.Count != 1 {
+		t.Errorf("Expected 1 sample, got %d", metric.Count)
 	}
 }
 
@@ -296,8 +297,52 @@ func TestHandleRecordLatencyConcurrent(t *testing.T) {
 		t.Errorf("Expected metric to exist")
 	}
 
-	if len(metric.Samples) != numGoroutines {
-		t.Errorf("Expected %d samples, got %d", numGoroutines, len(metric.Samples))
+	if metric.Count != uint64(numGoroutines) {
+		t.Errorf("Expected %d samples, got %d", numGoroutines, metric.Count)
+	}
+}
+
+// Test handleMetricsPrometheus renders bucket/sum/count lines for a recorded metric
+func TestHandleMetricsPrometheus(t *testing.T) {
+	store := createNewMetricStore()
+	server := newServer(store)
+
+	config := defaultMetricConfig("test-metric")
+	store.CreateMetric(config)
+	metric, _ := store.getMetric("test-metric")
+	metric.RecordLatency(5 * time.Millisecond)
+	metric.RecordLatency(50 * time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/metrics/prometheus", nil)
+	w := httptest.NewRecorder()
+
+	server.handleMetricsPrometheus(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `latency_bucket{id="test-metric",le="+Inf"} 2`) {
+		t.Errorf("Expected +Inf bucket count of 2, got body: %s", body)
+	}
+	if !strings.Contains(body, `latency_count{id="test-metric"} 2`) {
+		t.Errorf("Expected latency_count of 2, got body: %s", body)
+	}
+}
+
+// Test handleMetricsPrometheus with non-GET request
+func TestHandleMetricsPrometheusNonGET(t *testing.T) {
+	store := createNewMetricStore()
+	server := newServer(store)
+
+	req := httptest.NewRequest("POST", "/metrics/prometheus", nil)
+	w := httptest.NewRecorder()
+
+	server.handleMetricsPrometheus(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
 	}
 }
 
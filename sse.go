@@ -0,0 +1,178 @@
+// package latencyTracker
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultSubscribeInterval is used by handleSubscribe/handleSubscribeAll when
+// the request doesn't set "?interval=".
+const defaultSubscribeInterval = time.Second
+
+// minSubscribeInterval guards against a subscriber requesting an interval so
+// small it would just spin CalculateLatency.
+const minSubscribeInterval = 100 * time.Millisecond
+
+// sseKeepalive is how often a ":keepalive" comment is sent on an otherwise
+// idle subscription, so intermediate proxies don't time the connection out.
+const sseKeepalive = 15 * time.Second
+
+func parseSubscribeInterval(r *http.Request) (time.Duration, error) {
+	raw := r.URL.Query().Get("interval")
+	if raw == "" {
+		return defaultSubscribeInterval, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid 'interval' query param: %w", err)
+	}
+	if interval < minSubscribeInterval {
+		return 0, fmt.Errorf("'interval' must be at least %s", minSubscribeInterval)
+	}
+	return interval, nil
+}
+
+// handleSubscribe upgrades to an SSE stream of a single metric's snapshots.
+// Expected path: "/metrics/{id}/subscribe".
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only 'GET' requests are processed at this endpoint!", http.StatusMethodNotAllowed)
+		return
+	}
+
+	interval, err := parseSubscribeInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	snapshots, cancel, err := s.store.Subscribe(id, interval)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer cancel()
+
+	writeSSEHeaders(w)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case snapshot, ok := <-snapshots:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(w, snapshot); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleSubscribeAll is the "/metrics/subscribe" variant: it streams a fresh
+// slice of every metric's snapshot at the requested interval, the same shape
+// handleMetrics returns. It doesn't go through MetricStore.Subscribe since
+// that API is scoped to (and capped per) a single metric; it's capped via
+// AcquireGlobalSubscriber instead.
+func (s *Server) handleSubscribeAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only 'GET' requests are processed at this endpoint!", http.StatusMethodNotAllowed)
+		return
+	}
+
+	interval, err := parseSubscribeInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	release, err := s.store.AcquireGlobalSubscriber()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
+	writeSSEHeaders(w)
+	flusher.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if err := writeSSEEvent(w, s.allSnapshots()); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// allSnapshots returns a CalculateLatency snapshot for every metric currently
+// in the store, in the same fashion as handleMetrics.
+func (s *Server) allSnapshots() []MetricSnapshot {
+	s.store.mu.RLock()
+	metrics := make([]*Metric, 0, len(s.store.Metrics))
+	for _, m := range s.store.Metrics {
+		metrics = append(metrics, m)
+	}
+	s.store.mu.RUnlock()
+
+	snapshots := make([]MetricSnapshot, 0, len(metrics))
+	for _, m := range metrics {
+		snapshots = append(snapshots, m.CalculateLatency())
+	}
+	return snapshots
+}
+
+func writeSSEHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+func writeSSEEvent(w http.ResponseWriter, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", payload)
+	return err
+}
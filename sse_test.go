@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test subscribing via an httptest.NewServer end-to-end: multiple frames
+// should arrive, and cancelling the client's context should stop the stream.
+func TestHandleSubscribeMultipleFrames(t *testing.T) {
+	store := createNewMetricStore()
+	server := newServer(store)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics/", server.handleMetricsById)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	store.CreateMetric(defaultMetricConfig("sse-metric"))
+	metric, _ := store.getMetric("sse-metric")
+	metric.RecordLatency(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+"/metrics/sse-metric/subscribe?interval=150ms", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	frames := 0
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "event: snapshot") {
+			frames++
+			if frames >= 2 {
+				break
+			}
+		}
+	}
+	if frames < 2 {
+		t.Errorf("Expected at least 2 snapshot frames, got %d", frames)
+	}
+}
+
+// Test that cancelling the request context terminates handleSubscribe's
+// background ticking and releases the subscriber slot it held.
+func TestHandleSubscribeCancelStopsGoroutine(t *testing.T) {
+	store := createNewMetricStore()
+	server := newServer(store)
+	store.CreateMetric(defaultMetricConfig("sse-cancel"))
+
+	baseReq := httptest.NewRequest("GET", "/metrics/sse-cancel/subscribe?interval=100ms", nil)
+	ctx, cancel := context.WithCancel(baseReq.Context())
+	req := baseReq.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		server.handleSubscribe(w, req, "sse-cancel")
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected handleSubscribe to return after its context was cancelled")
+	}
+
+	metric, _ := store.getMetric("sse-cancel")
+	metric.mu.Lock()
+	subscribers := metric.subscribers
+	metric.mu.Unlock()
+	if subscribers != 0 {
+		t.Errorf("Expected subscriber count to return to 0 after cancel, got %d", subscribers)
+	}
+}
+
+// Test "/metrics/subscribe" through the same method-prefixed http.ServeMux
+// wiring main.go uses, not just by calling handleSubscribeAll directly: a
+// method-prefixed pattern like "GET /metrics/subscribe" only matches when
+// go.mod's go directive is >= 1.22, so a handler-level test alone wouldn't
+// have caught it being unreachable in the real router.
+func TestHandleSubscribeAllViaRealRouter(t *testing.T) {
+	store := createNewMetricStore()
+	server := newServer(store)
+	store.CreateMetric(defaultMetricConfig("all-metric"))
+	metric, _ := store.getMetric("all-metric")
+	metric.RecordLatency(10 * time.Millisecond)
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /metrics/subscribe", server.handleSubscribeAll)
+	router.HandleFunc("/metrics/", server.handleMetricsById)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+"/metrics/subscribe?interval=150ms", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 from the real router, got %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "event: snapshot") {
+			return
+		}
+	}
+	t.Error("Expected at least 1 snapshot frame from /metrics/subscribe")
+}
+
+// Test that MetricStore.Subscribe rejects new subscribers once a metric is
+// at its cap, and frees a slot again once cancelled.
+func TestSubscribeCapPerMetric(t *testing.T) {
+	store := createNewMetricStore()
+	store.CreateMetric(defaultMetricConfig("cap-metric"))
+
+	var cancels []func()
+	for i := 0; i < maxSubscribersPerMetric; i++ {
+		_, cancelFn, err := store.Subscribe("cap-metric", time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error on subscriber %d: %v", i, err)
+		}
+		cancels = append(cancels, cancelFn)
+	}
+
+	if _, _, err := store.Subscribe("cap-metric", time.Hour); err == nil {
+		t.Error("expected an error once the per-metric subscriber cap is reached")
+	}
+
+	cancels[0]()
+	if _, _, err := store.Subscribe("cap-metric", time.Hour); err != nil {
+		t.Errorf("expected a slot to free up after cancelling one subscriber, got: %v", err)
+	}
+
+	for _, c := range cancels[1:] {
+		c()
+	}
+}
+
+// Test that MetricStore.AcquireGlobalSubscriber rejects a new
+// handleSubscribeAll connection once the store is at its global cap, and
+// frees a slot again once released.
+func TestAcquireGlobalSubscriberCap(t *testing.T) {
+	store := createNewMetricStore()
+
+	var releases []func()
+	for i := 0; i < maxGlobalSubscribers; i++ {
+		release, err := store.AcquireGlobalSubscriber()
+		if err != nil {
+			t.Fatalf("unexpected error on subscriber %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+
+	if _, err := store.AcquireGlobalSubscriber(); err == nil {
+		t.Error("expected an error once the global subscriber cap is reached")
+	}
+
+	releases[0]()
+	if _, err := store.AcquireGlobalSubscriber(); err != nil {
+		t.Errorf("expected a slot to free up after releasing one subscriber, got: %v", err)
+	}
+
+	for _, r := range releases[1:] {
+		r()
+	}
+}
+
+// Test that handleSubscribeAll itself returns 429 once the store is at its
+// global subscriber cap, not just the underlying store method.
+func TestHandleSubscribeAllRejectsOverCap(t *testing.T) {
+	store := createNewMetricStore()
+	server := newServer(store)
+
+	var releases []func()
+	for i := 0; i < maxGlobalSubscribers; i++ {
+		release, err := store.AcquireGlobalSubscriber()
+		if err != nil {
+			t.Fatalf("unexpected error reserving subscriber %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+	defer func() {
+		for _, r := range releases {
+			r()
+		}
+	}()
+
+	req := httptest.NewRequest("GET", "/metrics/subscribe", nil)
+	w := httptest.NewRecorder()
+	server.handleSubscribeAll(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 once over the global subscriber cap, got %d", w.Code)
+	}
+}
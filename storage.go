@@ -2,41 +2,112 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
-	"math"
+	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/ashutosh-pandey10/latency-tracker/otlp"
 )
 
-// Takes Metric receiver, adds new latency value to the slice of
-// LatencySamples. Makes sure only MaxSamples number of latencies
-// are considered for a given ID/API invocation
+// digestBucket is the width of one sub-digest in the ring kept per Metric:
+// one t-digest per second, so CalculateLatency only has to merge the
+// sub-digests whose bucket falls inside Window instead of re-sketching
+// everything on every read.
+const digestBucket = time.Second
+
+// Takes Metric receiver, folds the latency into the current second's
+// sub-digest (see digest.go) and the Prometheus histogram counters.
 func (m *Metric) RecordLatency(latency time.Duration) bool {
-	if latency <= 0 {
+	return m.RecordSample(LatencySample{LatencyVal: latency, RecordedAt: time.Now()})
+}
+
+// RecordSample is the fuller form of RecordLatency: it also accepts the
+// HTTP status Middleware observed, so it can be tallied in StatusCounts.
+// Status is optional (zero-valued) for callers, like handleRecordLatency,
+// that only ever know the latency itself.
+func (m *Metric) RecordSample(sample LatencySample) bool {
+	if sample.LatencyVal <= 0 {
 		fmt.Println("Value passed for latency can't be <= '0'")
 		return false
 	}
-	sample := LatencySample{latency, time.Now()}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.Samples = append(m.Samples, sample)
-	if len(m.Samples) > m.Config.MaxSamples {
-		// Ensuring that only "MaxSamples" latency records are kept
-		m.Samples = m.Samples[1:]
+	m.insertDigest(sample.LatencyVal, sample.RecordedAt)
+	m.recordHistogram(sample.LatencyVal)
+	if sample.Status != 0 {
+		if m.StatusCounts == nil {
+			m.StatusCounts = make(map[int]uint64)
+		}
+		m.StatusCounts[sample.Status]++
 	}
 	return true
 }
 
+// insertDigest locates (creating if necessary) the sub-digest for the
+// bucket "now" falls into and folds latency into it. Must be called with
+// m.mu held.
+func (m *Metric) insertDigest(latency time.Duration, now time.Time) {
+	if m.digests == nil {
+		m.digests = make(map[int64]*tdigest)
+	}
+	m.evictStaleDigests(now)
+
+	bucket := now.Truncate(digestBucket).UnixNano()
+	td, ok := m.digests[bucket]
+	if !ok {
+		td = newTDigest(float64(m.Config.MaxSamples))
+		m.digests[bucket] = td
+	}
+	td.insert(float64(latency))
+}
+
+// evictStaleDigests drops sub-digests that have already aged out of Window.
+// CalculateLatency does this too on its own read path, but a metric that's
+// recorded against and never read (scraped/queried/subscribed to) would
+// otherwise never hit that path, growing m.digests by one bucket every
+// digestBucket forever. Calling this from the write path as well keeps
+// memory bounded by Window regardless of whether anyone's reading. Must be
+// called with m.mu held.
+func (m *Metric) evictStaleDigests(now time.Time) {
+	cutoff := now.Add(-m.Config.Window).Truncate(digestBucket).UnixNano()
+	for bucket := range m.digests {
+		if bucket < cutoff {
+			delete(m.digests, bucket)
+		}
+	}
+}
+
+// recordHistogram folds latency into the cumulative bucket counters backing
+// "/metrics/prometheus". Must be called with m.mu held.
+func (m *Metric) recordHistogram(latency time.Duration) {
+	if m.BucketCounts == nil {
+		// +1 for the implicit trailing "+Inf" bucket.
+		m.BucketCounts = make([]uint64, len(m.Config.Buckets)+1)
+	}
+	for i, le := range m.Config.Buckets {
+		if latency <= le {
+			m.BucketCounts[i]++
+		}
+	}
+	m.BucketCounts[len(m.BucketCounts)-1]++ // "+Inf" always matches
+	m.Sum += latency
+	m.Count++
+}
+
+// defaultPercentiles is used by CalculateLatency when MetricConfig.Percentiles
+// is left empty.
+var defaultPercentiles = []int{50, 95, 99}
+
 func (m *Metric) CalculateLatency() MetricSnapshot {
-	// Considering only those latency samples, that are inside the configured
-	// window size, rest will be ignored
-	var active []LatencySample
-	cutoff := time.Now().Add(-m.Config.Window)
-	// WHY IS THIS WINDOWING BEING IMPLEMENTED AT READ-TIME AND NOT WRITE TIME?
+	// WHY IS WINDOWING (WHICH DIGESTS COUNT) DECIDED AT READ-TIME, NOT WRITE TIME?
 	//
 	// IDEA : Writes store facts. Reads apply interpretation.
 	// Fact: “This latency happened at time T”
@@ -45,15 +116,27 @@ func (m *Metric) CalculateLatency() MetricSnapshot {
 	// You interpret them at read time.
 	// That’s the clean separation.
 	//
-	// Latency samples are timestamped at ingestion.
-	// Sliding windows are enforced at read time because windowing is a query concern,
-	// not an ingestion concern.
-	// This keeps writes fast, avoids complex cleanup logic, and ensures percentiles
-	// always reflect current system behavior.
+	// Latency samples are timestamped (via the bucket they land in) at
+	// ingestion. Sliding windows are interpreted at read time because
+	// windowing is a query concern, not an ingestion concern. Eviction of
+	// definitely-stale digests, on the other hand, also happens on the write
+	// path (see insertDigest/evictStaleDigests) so memory stays bounded by
+	// Window even for a metric nobody ever reads; evicting again here just
+	// keeps a metric that's read but rarely written from carrying stale
+	// digests between reads.
+	now := time.Now()
+
 	m.mu.Lock()
-	for _, record := range m.Samples {
-		if record.RecordedAt.After(cutoff) {
-			active = append(active, record)
+	m.evictStaleDigests(now)
+	merged := newTDigest(float64(m.Config.MaxSamples))
+	for _, td := range m.digests {
+		merged.merge(td)
+	}
+	var statusCounts map[int]uint64
+	if len(m.StatusCounts) > 0 {
+		statusCounts = make(map[int]uint64, len(m.StatusCounts))
+		for status, count := range m.StatusCounts {
+			statusCounts[status] = count
 		}
 	}
 	// Instead of defer, used the unlock() directly
@@ -62,39 +145,37 @@ func (m *Metric) CalculateLatency() MetricSnapshot {
 	m.mu.Unlock()
 
 	snapshot := MetricSnapshot{
-		MetricID: m.Config.ID,
-		Window:   m.Config.Window,
-		Count:    len(active),
-	}
-	if len(active) > 0 {
-		latencies := make([]time.Duration, 0)
-		for _, l := range active {
-			latencies = append(latencies, l.LatencyVal)
-		}
-		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		MetricID:     m.Config.ID,
+		Window:       m.Config.Window,
+		StatusCounts: statusCounts,
+		Count:        int(merged.count),
+	}
+	if merged.count == 0 {
+		return snapshot
+	}
 
-		snapshot.P50 = computePercentile(latencies, 50)
-		snapshot.P95 = computePercentile(latencies, 95)
-		snapshot.P99 = computePercentile(latencies, 99)
+	percentiles := m.Config.Percentiles
+	if len(percentiles) == 0 {
+		percentiles = defaultPercentiles
+	}
+	snapshot.Percentiles = make(map[int]time.Duration, len(percentiles))
+	for _, p := range percentiles {
+		if v, ok := merged.quantile(float64(p) / 100); ok {
+			snapshot.Percentiles[p] = time.Duration(v)
+		}
 	}
-	// Since LatencySample has time.Duration as data type, it cannot be directly compared
+	snapshot.P50 = percentileDuration(snapshot.Percentiles, 50)
+	snapshot.P95 = percentileDuration(snapshot.Percentiles, 95)
+	snapshot.P99 = percentileDuration(snapshot.Percentiles, 99)
 	return snapshot
 }
 
-func computePercentile(latencies []time.Duration, percentile int) *time.Duration {
-	N := len(latencies)
-	if N == 0 {
+func percentileDuration(percentiles map[int]time.Duration, p int) *time.Duration {
+	v, ok := percentiles[p]
+	if !ok {
 		return nil
 	}
-	idxPercentile := int(math.Ceil(((float64(percentile) / 100.0) * float64(N)))) - 1
-	if idxPercentile < 0 {
-		idxPercentile = 0
-	}
-	if idxPercentile >= N {
-		idxPercentile = N - 1
-	}
-	val := latencies[idxPercentile]
-	return &val
+	return &v
 }
 
 // If you tried to do this: var m map[string]*Metric (without make)
@@ -116,9 +197,11 @@ func (ms *MetricStore) CreateMetric(config MetricConfig) (*Metric, error) {
 	if config.MaxSamples <= 0 {
 		return nil, errors.New("'MaxSamples' can't be less than 1!")
 	}
+	if !sort.SliceIsSorted(config.Buckets, func(i, j int) bool { return config.Buckets[i] < config.Buckets[j] }) {
+		return nil, errors.New("'Buckets' must be in ascending order!")
+	}
 	metric := &Metric{
-		Config:  config,
-		Samples: make([]LatencySample, 0),
+		Config: config,
 	}
 
 	ms.mu.Lock()
@@ -146,11 +229,203 @@ func (ms *MetricStore) getOrCreateMetric(id string) *Metric {
 		return metric
 	}
 	metric := &Metric{
-		Config:  defaultMetricConfig(id),
-		Samples: make([]LatencySample, 0),
+		Config: defaultMetricConfig(id),
 	}
 
-	ms.Metrics = make(map[string]*Metric)
+	// NOTE: used to reassign ms.Metrics = make(map[string]*Metric) here,
+	// which discarded every other metric in the store on each cache miss.
 	ms.Metrics[id] = metric
 	return metric
 }
+
+// maxSubscribersPerMetric bounds how many concurrent Subscribe listeners a
+// single Metric will tick snapshots to, so one metric's SSE subscribers
+// can't fan out an unbounded number of ticker goroutines.
+const maxSubscribersPerMetric = 16
+
+// Subscribe registers a listener that receives a fresh MetricSnapshot for
+// metric id every interval, until the returned cancel func is called. The
+// metric is created via getOrCreateMetric if it doesn't exist yet, matching
+// how recording a latency auto-creates one. The channel is closed once the
+// listener is cancelled, after which the subscriber slot it held is freed.
+func (ms *MetricStore) Subscribe(id string, interval time.Duration) (<-chan MetricSnapshot, func(), error) {
+	metric := ms.getOrCreateMetric(id)
+
+	metric.mu.Lock()
+	if metric.subscribers >= maxSubscribersPerMetric {
+		metric.mu.Unlock()
+		return nil, nil, fmt.Errorf("metric %q already has the maximum of %d subscribers", id, maxSubscribersPerMetric)
+	}
+	metric.subscribers++
+	metric.mu.Unlock()
+
+	snapshots := make(chan MetricSnapshot, 1)
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(done)
+			metric.mu.Lock()
+			metric.subscribers--
+			metric.mu.Unlock()
+		})
+	}
+
+	go func() {
+		defer close(snapshots)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				snapshot := metric.CalculateLatency()
+				select {
+				case snapshots <- snapshot:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	return snapshots, cancel, nil
+}
+
+// maxGlobalSubscribers bounds how many concurrent handleSubscribeAll
+// ("/metrics/subscribe") connections can be open at once. That endpoint
+// doesn't go through Subscribe/maxSubscribersPerMetric (it's not scoped to
+// one metric), but it's the more expensive of the two SSE endpoints - every
+// tick runs CalculateLatency over every metric in the store - so it needs
+// its own cap just the same.
+const maxGlobalSubscribers = 16
+
+// AcquireGlobalSubscriber reserves a handleSubscribeAll connection slot,
+// returning a release func to give it back once the connection ends, or an
+// error if the store already has maxGlobalSubscribers open.
+func (ms *MetricStore) AcquireGlobalSubscriber() (func(), error) {
+	ms.mu.Lock()
+	if ms.allSubscribers >= maxGlobalSubscribers {
+		ms.mu.Unlock()
+		return nil, fmt.Errorf("already have the maximum of %d subscribers to all metrics", maxGlobalSubscribers)
+	}
+	ms.allSubscribers++
+	ms.mu.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			ms.mu.Lock()
+			ms.allSubscribers--
+			ms.mu.Unlock()
+		})
+	}
+	return release, nil
+}
+
+// defaultBuckets are the histogram bucket upper bounds used by
+// defaultMetricConfig, tuned for typical in-process request latencies
+// (5ms..5s). Metrics created via MetricStore.CreateMetric can supply their
+// own Buckets instead.
+var defaultBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// writePrometheus renders metrics in the Prometheus text exposition format
+// (https://prometheus.io/docs/instrumenting/exposition_formats/). Each Metric
+// becomes one series (labelled by "id") of a shared "latency" histogram
+// family: "latency_bucket{le=...}", "latency_sum" and "latency_count". These
+// are cumulative-since-start counters (see Metric.BucketCounts), independent
+// of the sliding Window used by CalculateLatency, so they behave the way
+// Prometheus histograms are expected to under rate()/increase().
+func writePrometheus(w io.Writer, metrics []*Metric) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP latency Observed latency in seconds.")
+	fmt.Fprintln(bw, "# TYPE latency histogram")
+
+	sorted := make([]*Metric, len(metrics))
+	copy(sorted, metrics)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Config.ID < sorted[j].Config.ID })
+
+	for _, m := range sorted {
+		m.mu.Lock()
+		id := escapeLabelValue(m.Config.ID)
+		buckets := m.Config.Buckets
+		counts := make([]uint64, len(m.BucketCounts))
+		copy(counts, m.BucketCounts)
+		sum := m.Sum
+		count := m.Count
+		m.mu.Unlock()
+
+		for i, le := range buckets {
+			fmt.Fprintf(bw, "latency_bucket{id=\"%s\",le=\"%s\"} %d\n", id, formatSeconds(le), counts[i])
+		}
+		fmt.Fprintf(bw, "latency_bucket{id=\"%s\",le=\"+Inf\"} %d\n", id, counts[len(counts)-1])
+		fmt.Fprintf(bw, "latency_sum{id=\"%s\"} %s\n", id, formatSeconds(sum))
+		fmt.Fprintf(bw, "latency_count{id=\"%s\"} %d\n", id, count)
+	}
+
+	return bw.Flush()
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// escapeLabelValue escapes a string per the Prometheus exposition format's
+// label-value rules: backslash, double-quote and newline are backslash-escaped.
+func escapeLabelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// Export adapts MetricStore to otlp.Store, so the otlp package's Exporter
+// can build an OTLP payload without importing package main (which isn't
+// possible for a "main" package) and without the otlp package needing to
+// know anything about Metric/MetricStore. The cumulative bucket counts
+// mirror writePrometheus; percentiles are the same windowed view
+// CalculateLatency reports elsewhere.
+func (ms *MetricStore) Export() []otlp.MetricExport {
+	ms.mu.RLock()
+	metrics := make([]*Metric, 0, len(ms.Metrics))
+	for _, m := range ms.Metrics {
+		metrics = append(metrics, m)
+	}
+	ms.mu.RUnlock()
+
+	exports := make([]otlp.MetricExport, 0, len(metrics))
+	for _, m := range metrics {
+		snapshot := m.CalculateLatency()
+
+		m.mu.Lock()
+		buckets := m.Config.Buckets
+		counts := make([]uint64, len(m.BucketCounts))
+		copy(counts, m.BucketCounts)
+		sum := m.Sum
+		count := m.Count
+		m.mu.Unlock()
+
+		exports = append(exports, otlp.MetricExport{
+			ID:           m.Config.ID,
+			Count:        count,
+			Sum:          sum,
+			Buckets:      buckets,
+			BucketCounts: counts,
+			Percentiles:  snapshot.Percentiles,
+		})
+	}
+	return exports
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that a metric recorded against continuously, but never read via
+// CalculateLatency/Subscribe, still keeps its digest ring bounded by Window
+// instead of growing by one sub-digest per digestBucket forever.
+func TestRecordLatencyEvictsStaleDigestsOnWrite(t *testing.T) {
+	metric := &Metric{Config: MetricConfig{ID: "write-only", Window: 5 * time.Second, MaxSamples: 50}}
+
+	base := time.Now()
+	for i := 0; i < 1000; i++ {
+		metric.RecordSample(LatencySample{
+			LatencyVal: time.Millisecond,
+			RecordedAt: base.Add(time.Duration(i) * digestBucket),
+		})
+	}
+
+	metric.mu.Lock()
+	got := len(metric.digests)
+	metric.mu.Unlock()
+
+	// Window is 5 buckets wide; allow a little slack for the in-progress
+	// bucket, but nowhere near the 1000 buckets written over the test.
+	if want := int(metric.Config.Window/digestBucket) + 2; got > want {
+		t.Errorf("Expected digests to stay bounded by Window (~%d buckets), got %d", want, got)
+	}
+}
+
+// Test that CreateMetric rejects a Buckets slice that isn't in ascending
+// order: recordHistogram's cumulative counts only stay monotonic, as a
+// Prometheus histogram requires, if the bucket bounds are sorted.
+func TestCreateMetricRejectsUnsortedBuckets(t *testing.T) {
+	store := createNewMetricStore()
+	config := MetricConfig{
+		ID:         "unsorted-buckets",
+		Window:     time.Minute,
+		MaxSamples: 50,
+		Buckets:    []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, 200 * time.Millisecond},
+	}
+
+	if _, err := store.CreateMetric(config); err == nil {
+		t.Error("expected an error creating a metric with unsorted Buckets")
+	}
+}